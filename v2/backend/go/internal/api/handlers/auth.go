@@ -35,12 +35,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"regexp"
 	"strings"
 	"time"
 
 	"sage-ai-v2/internal/models"
 	"sage-ai-v2/internal/services"
+	"sage-ai-v2/internal/services/session"
+	"sage-ai-v2/pkg/hmacauth"
 	"sage-ai-v2/pkg/logger"
 )
 
@@ -48,14 +53,127 @@ import (
 type AuthServiceInterface interface {
     SignIn(ctx context.Context, req models.SignInRequest) (*models.AuthResponse, error)
     SignUp(ctx context.Context, req models.SignUpRequest) (*models.AuthResponse, error)
-    OAuthSignIn(ctx context.Context, provider, code, redirectURI string) (*models.AuthResponse, error)
-    GetOAuthURL(provider, redirectURI string) (string, error)
+    OAuthSignIn(ctx context.Context, provider, code, redirectURI, codeVerifier string) (*models.AuthResponse, error)
+    GetOAuthURL(provider, redirectURI, state, codeChallenge string) (string, error)
     VerifyToken(token string) (string, error)
     GetUserByID(ctx context.Context, id string) (*models.User, error)
+    // Refresh exchanges a provider refresh token for a new AuthResponse.
+    // Called by AuthMiddleware/GetUserHandler once a session is past its
+    // RefreshAfter timestamp.
+    Refresh(ctx context.Context, refreshToken string) (*models.AuthResponse, error)
+    // IssueAppToken mints an opaque API token scoped to scopes for
+    // userID, granted to the third-party client clientID during the
+    // oauth2provider authorization_code/refresh_token exchange.
+    IssueAppToken(ctx context.Context, userID, clientID string, scopes []string) (*models.AuthResponse, error)
+    // ValidateAppToken resolves a previously issued app token back to the
+    // user it was issued for, the client it was issued to, and the scopes
+    // it was granted. AuthMiddleware accepts these tokens via
+    // Authorization: Bearer alongside session cookies; the refresh_token
+    // grant uses clientID to confirm a refresh token is only ever redeemed
+    // by the client it was issued to.
+    ValidateAppToken(token string) (userID string, clientID string, scopes []string, err error)
+    // RevokeAppToken invalidates a previously issued app token so it can
+    // no longer pass ValidateAppToken. Used by the /api/oauth/revoke
+    // handler.
+    RevokeAppToken(token string) error
+}
+
+// oauthStateCookieName is the HttpOnly cookie that carries the encrypted
+// state/PKCE verifier pair between OAuthURLHandler and OAuthSignInHandler.
+const oauthStateCookieName = "oauth_state"
+
+// oauthStateCookieMaxAge bounds how long a browser can sit on the
+// provider's consent screen before the flow is considered abandoned.
+const oauthStateCookieMaxAge = 10 * time.Minute
+
+// sessionCookieName is the only thing the browser ever holds for an
+// authenticated user: an opaque ID resolved against session.Store. The
+// actual provider tokens never leave the server.
+const sessionCookieName = "session_id"
+
+// defaultSessionDuration bounds how long a session can live before the
+// user has to sign in again, regardless of refreshing.
+const defaultSessionDuration = 7 * 24 * time.Hour
+
+// hmacSharedKey returns the shared secret used to sign requests forwarded
+// to upstream services, or nil if HMAC_SHARED_KEY isn't configured (in
+// which case AuthMiddleware skips signing entirely).
+func hmacSharedKey() []byte {
+	if key := os.Getenv("HMAC_SHARED_KEY"); key != "" {
+		return []byte(key)
+	}
+	return nil
+}
+
+// hmacSignedHeaders parses the comma-separated HMAC_SIGNED_HEADERS env
+// var into the extra headers folded into the upstream signature on top
+// of hmacauth's fixed default set.
+func hmacSignedHeaders() []string {
+	v := os.Getenv("HMAC_SIGNED_HEADERS")
+	if v == "" {
+		return nil
+	}
+	return strings.Split(v, ",")
+}
+
+// attachForwardedIdentity sets the X-Forwarded-* identity headers on r
+// for the upstream service, then signs the request with Sage-Signature
+// so the upstream can verify sage-ai's Go layer actually vouches for
+// them, rather than trusting any caller inside the network.
+func attachForwardedIdentity(r *http.Request, userID, email, accessToken string) {
+	r.Header.Set("X-Forwarded-User", userID)
+	r.Header.Set("X-Forwarded-Email", email)
+	r.Header.Set("X-Forwarded-Access-Token", accessToken)
+
+	key := hmacSharedKey()
+	if key == nil {
+		return
+	}
+	r.Header.Set(hmacauth.SignatureHeader, hmacauth.Sign(key, r, hmacSignedHeaders()...))
+}
+
+// cookieRefreshInterval controls how often a live session's access token
+// is proactively refreshed, mirroring oauth2-proxy's cookie_refresh
+// setting. Configurable via the COOKIE_REFRESH env var (e.g. "1h").
+func cookieRefreshInterval() time.Duration {
+	if v := os.Getenv("COOKIE_REFRESH"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return time.Hour
 }
 
 type AuthHandler struct {
-	authService AuthServiceInterface
+	authService  AuthServiceInterface
+	sessionStore session.Store
+
+	skipAuthRegexes   []*regexp.Regexp
+	trustedIPNets     []*net.IPNet
+	trustedIPHeader   string
+	skipAuthPreflight bool
+}
+
+// AuthOptions configures bypass rules on AuthMiddleware, mirroring
+// oauth2-proxy's TrustedIPs/skipAuthRegex/skipAuthPreflight knobs. Routes
+// like health checks, webhook receivers, and internal-cluster calls can
+// be exposed this way without copy-pasting middleware wiring around them.
+type AuthOptions struct {
+	// SkipAuthRegex is a list of regular expressions matched against
+	// r.URL.Path; a match lets the request through with userID set to
+	// "anonymous".
+	SkipAuthRegex []string
+	// TrustedIPNets is a list of CIDRs (e.g. "10.0.0.0/8"); a request
+	// whose client IP falls in one of them is let through with userID
+	// set to "trusted:<ip>".
+	TrustedIPNets []string
+	// TrustedIPHeader is the header to read the client IP from (e.g.
+	// "X-Real-IP"), falling back to r.RemoteAddr if unset or absent.
+	TrustedIPHeader string
+	// SkipAuthPreflight lets CORS preflight (OPTIONS) requests through
+	// without authentication, since the browser never attaches cookies
+	// or an Authorization header to them.
+	SkipAuthPreflight bool
 }
 
 // // Constructor function
@@ -63,8 +181,173 @@ type AuthHandler struct {
 // 	return &AuthHandler{authService: authService}
 // }
 
-func NewAuthHandler(authService AuthServiceInterface) *AuthHandler {
-    return &AuthHandler{authService: authService}
+// // Previous constructor, before sessions moved server-side
+// func NewAuthHandler(authService AuthServiceInterface) *AuthHandler {
+//     return &AuthHandler{authService: authService}
+// }
+
+func NewAuthHandler(authService AuthServiceInterface, sessionStore session.Store) *AuthHandler {
+    return &AuthHandler{authService: authService, sessionStore: sessionStore}
+}
+
+// NewAuthHandlerWithOptions is NewAuthHandler plus AuthMiddleware bypass
+// rules. Regexes and CIDRs are compiled/parsed once up front so the
+// middleware never recompiles or reparses them per request.
+func NewAuthHandlerWithOptions(authService AuthServiceInterface, sessionStore session.Store, opts AuthOptions) (*AuthHandler, error) {
+	h := &AuthHandler{
+		authService:       authService,
+		sessionStore:      sessionStore,
+		trustedIPHeader:   opts.TrustedIPHeader,
+		skipAuthPreflight: opts.SkipAuthPreflight,
+	}
+
+	for _, pattern := range opts.SkipAuthRegex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid skip-auth regex %q: %w", pattern, err)
+		}
+		h.skipAuthRegexes = append(h.skipAuthRegexes, re)
+	}
+
+	for _, cidr := range opts.TrustedIPNets {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted IP net %q: %w", cidr, err)
+		}
+		h.trustedIPNets = append(h.trustedIPNets, ipNet)
+	}
+
+	return h, nil
+}
+
+// clientIP resolves the caller's IP from h.trustedIPHeader, falling back
+// to r.RemoteAddr if the header isn't configured or absent.
+func (h *AuthHandler) clientIP(r *http.Request) string {
+	if h.trustedIPHeader != "" {
+		if v := r.Header.Get(h.trustedIPHeader); v != "" {
+			return v
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// isTrustedIP reports whether ip falls inside any of h.trustedIPNets.
+func (h *AuthHandler) isTrustedIP(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, ipNet := range h.trustedIPNets {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// startSession saves a new session derived from resp and sets the
+// session-ID cookie on w. It's the only place a session cookie is minted.
+func (h *AuthHandler) startSession(w http.ResponseWriter, r *http.Request, resp *models.AuthResponse) error {
+	now := time.Now()
+	expiresAt := now.Add(defaultSessionDuration)
+
+	var userID string
+	if resp.User != nil {
+		userID = resp.User.ID
+	}
+
+	sess := &session.Session{
+		UserID:       userID,
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		IDToken:      resp.IDToken,
+		CreatedAt:    now,
+		ExpiresAt:    expiresAt,
+		RefreshAfter: now.Add(cookieRefreshInterval()),
+	}
+
+	id, err := h.sessionStore.Save(sess)
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    id,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int(defaultSessionDuration / time.Second),
+	})
+	return nil
+}
+
+// resolveSession reads the session-ID cookie off r, looks it up, and
+// transparently refreshes it if it's past RefreshAfter. It's shared by
+// AuthMiddleware and GetUserHandler so both see the same freshness logic.
+func (h *AuthHandler) resolveSession(ctx context.Context, r *http.Request) (*session.Session, error) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return nil, fmt.Errorf("missing session cookie")
+	}
+
+	sess, err := h.sessionStore.Get(cookie.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	if sess.NeedsRefresh() {
+		resp, err := h.authService.Refresh(ctx, sess.RefreshToken)
+		if err != nil {
+			// Fail closed: a refresh failure (revoked consent, compromised
+			// account response, expired refresh token, ...) must not leave
+			// the stale access token usable for the rest of its 7-day
+			// session lifetime. Drop the session and force re-auth.
+			logger.ErrorLogger.Printf("Error refreshing session: %v", err)
+			if delErr := h.sessionStore.Delete(cookie.Value); delErr != nil {
+				logger.ErrorLogger.Printf("Error deleting unrefreshable session: %v", delErr)
+			}
+			return nil, fmt.Errorf("failed to refresh session: %w", err)
+		}
+
+		now := time.Now()
+		sess.AccessToken = resp.AccessToken
+		if resp.RefreshToken != "" {
+			sess.RefreshToken = resp.RefreshToken
+		}
+		sess.RefreshAfter = now.Add(cookieRefreshInterval())
+
+		if err := h.sessionStore.Update(cookie.Value, sess); err != nil {
+			logger.ErrorLogger.Printf("Error persisting refreshed session: %v", err)
+		}
+	}
+
+	return sess, nil
+}
+
+// resolveIdentity resolves the caller of r to a userID and the access
+// token to forward upstream, accepting either an oauth2provider app
+// token (Authorization: Bearer) or a session cookie - the same
+// precedence AuthMiddleware uses, so any handler that needs to identify
+// the caller directly (rather than trusting context set by the
+// middleware) sees the same rules.
+func (h *AuthHandler) resolveIdentity(r *http.Request) (userID, accessToken string, err error) {
+	if authHeader := r.Header.Get("Authorization"); len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+		token := authHeader[7:]
+		if userID, _, _, err := h.authService.ValidateAppToken(token); err == nil {
+			return userID, token, nil
+		}
+	}
+
+	sess, err := h.resolveSession(r.Context(), r)
+	if err != nil {
+		return "", "", err
+	}
+	return sess.UserID, sess.AccessToken, nil
 }
 
 // SignInHandler handles user sign-in with email/password
@@ -118,21 +401,18 @@ func (h *AuthHandler) SignInHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Set auth cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "auth_token",
-		Value:    resp.AccessToken,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   r.TLS != nil, // Set to true in production with HTTPS
-		SameSite: http.SameSiteLaxMode,
-		MaxAge:   int(time.Hour * 24 * 7 / time.Second), // 7 days
-	})
+	// Start a server-side session; the browser only gets the session ID
+	if err := h.startSession(w, r, resp); err != nil {
+		logger.ErrorLogger.Printf("Error starting session: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
 
-	// Return response
+	// Return response. The session already holds the provider tokens
+	// server-side; the browser only needs the user profile + expiry.
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
+	if err := json.NewEncoder(w).Encode(models.NewPublicAuthResponse(resp)); err != nil {
 		logger.ErrorLogger.Printf("Error encoding response: %v", err)
 	}
 }
@@ -185,21 +465,18 @@ func (h *AuthHandler) SignUpHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Set auth cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "auth_token",
-		Value:    resp.AccessToken,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   r.TLS != nil, // Set to true in production with HTTPS
-		SameSite: http.SameSiteLaxMode,
-		MaxAge:   int(time.Hour * 24 * 7 / time.Second), // 7 days
-	})
+	// Start a server-side session; the browser only gets the session ID
+	if err := h.startSession(w, r, resp); err != nil {
+		logger.ErrorLogger.Printf("Error starting session: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
 
-	// Return response
+	// Return response. The session already holds the provider tokens
+	// server-side; the browser only needs the user profile + expiry.
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
+	if err := json.NewEncoder(w).Encode(models.NewPublicAuthResponse(resp)); err != nil {
 		logger.ErrorLogger.Printf("Error encoding response: %v", err)
 	}
 }
@@ -272,19 +549,51 @@ func (h *AuthHandler) OAuthSignInHandler(w http.ResponseWriter, r *http.Request)
         return
     }
 
+    // The oauth_state cookie is required: it's how we know this callback
+    // belongs to a flow we actually started, not a forged/replayed one.
+    stateCookie, err := r.Cookie(oauthStateCookieName)
+    if err != nil {
+        w.WriteHeader(http.StatusBadRequest)
+        json.NewEncoder(w).Encode(map[string]interface{}{
+            "error":   true,
+            "message": "Missing or expired OAuth state",
+        })
+        return
+    }
+
+    expectedState, codeVerifier, err := services.DecryptOAuthState(stateCookie.Value)
+    if err != nil {
+        logger.ErrorLogger.Printf("Error decrypting OAuth state: %v", err)
+        w.WriteHeader(http.StatusBadRequest)
+        json.NewEncoder(w).Encode(map[string]interface{}{
+            "error":   true,
+            "message": "Invalid OAuth state",
+        })
+        return
+    }
+    if req.State == "" || req.State != expectedState {
+        w.WriteHeader(http.StatusBadRequest)
+        json.NewEncoder(w).Encode(map[string]interface{}{
+            "error":   true,
+            "message": "OAuth state mismatch",
+        })
+        return
+    }
+
+    // State has been consumed; clear it so it can't be replayed
+    http.SetCookie(w, &http.Cookie{
+        Name:     oauthStateCookieName,
+        Value:    "",
+        Path:     "/",
+        HttpOnly: true,
+        Secure:   r.TLS != nil,
+        SameSite: http.SameSiteLaxMode,
+        MaxAge:   -1,
+    })
+
     // Sign in user with OAuth
     ctx := r.Context()
-    // resp, err := h.authService.OAuthSignIn(ctx, provider, req.Code, req.RedirectURI)
-    // if err != nil {
-    //     logger.ErrorLogger.Printf("OAuth error: %v", err)
-    //     w.WriteHeader(http.StatusInternalServerError)
-    //     json.NewEncoder(w).Encode(map[string]interface{}{
-    //         "error": true,
-    //         "message": fmt.Sprintf("OAuth authentication failed: %v", err),
-    //     })
-    //     return
-    // }
-	resp, err := h.authService.OAuthSignIn(ctx, provider, req.Code, req.RedirectURI)
+	resp, err := h.authService.OAuthSignIn(ctx, provider, req.Code, req.RedirectURI, codeVerifier)
 	if err != nil {
 		logger.ErrorLogger.Printf("OAuth error: %v", err)
 		
@@ -306,20 +615,21 @@ func (h *AuthHandler) OAuthSignInHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-    // Set auth cookie
-    http.SetCookie(w, &http.Cookie{
-        Name:     "auth_token",
-        Value:    resp.AccessToken,
-        Path:     "/",
-        HttpOnly: true,
-        Secure:   r.TLS != nil,
-        SameSite: http.SameSiteLaxMode,
-        MaxAge:   int(time.Hour * 24 * 7 / time.Second),
-    })
+    // Start a server-side session; the browser only gets the session ID
+    if err := h.startSession(w, r, resp); err != nil {
+        logger.ErrorLogger.Printf("Error starting session: %v", err)
+        w.WriteHeader(http.StatusInternalServerError)
+        json.NewEncoder(w).Encode(map[string]interface{}{
+            "error":   true,
+            "message": "Failed to start session",
+        })
+        return
+    }
 
-    // Return response
+    // Return response. The session already holds the provider tokens
+    // server-side; the browser only needs the user profile + expiry.
     w.WriteHeader(http.StatusOK)
-    json.NewEncoder(w).Encode(resp)
+    json.NewEncoder(w).Encode(models.NewPublicAuthResponse(resp))
 }
 
 // OAuthURLHandler returns the URL for OAuth authentication
@@ -355,14 +665,46 @@ func (h *AuthHandler) OAuthURLHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Mint a fresh state and PKCE pair for this authorization attempt
+	state, err := services.GenerateOAuthState()
+	if err != nil {
+		logger.ErrorLogger.Printf("Error generating OAuth state: %v", err)
+		http.Error(w, "Failed to start OAuth flow", http.StatusInternalServerError)
+		return
+	}
+	codeVerifier, codeChallenge, err := services.GeneratePKCE()
+	if err != nil {
+		logger.ErrorLogger.Printf("Error generating PKCE verifier: %v", err)
+		http.Error(w, "Failed to start OAuth flow", http.StatusInternalServerError)
+		return
+	}
+
 	// Get OAuth URL
-	url, err := h.authService.GetOAuthURL(provider, redirectURI)
+	url, err := h.authService.GetOAuthURL(provider, redirectURI, state, codeChallenge)
 	if err != nil {
 		logger.ErrorLogger.Printf("Error getting OAuth URL: %v", err)
 		http.Error(w, fmt.Sprintf("Failed to get OAuth URL: %v", err), http.StatusInternalServerError)
 		return
 	}
 
+	// Stash state + verifier in a short-lived, encrypted HttpOnly cookie so
+	// OAuthSignInHandler can bind the callback back to this request
+	encryptedState, err := services.EncryptOAuthState(state, codeVerifier)
+	if err != nil {
+		logger.ErrorLogger.Printf("Error encrypting OAuth state: %v", err)
+		http.Error(w, "Failed to start OAuth flow", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookieName,
+		Value:    encryptedState,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int(oauthStateCookieMaxAge / time.Second),
+	})
+
 	// Return response
 	response := map[string]string{"url": url}
 	w.Header().Set("Content-Type", "application/json")
@@ -391,9 +733,17 @@ func (h *AuthHandler) SignOutHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Clear auth cookie
+	// Delete the server-side session so sign-out is real, not just a
+	// client-side cookie clear
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		if err := h.sessionStore.Delete(cookie.Value); err != nil {
+			logger.ErrorLogger.Printf("Error deleting session: %v", err)
+		}
+	}
+
+	// Clear session cookie
 	http.SetCookie(w, &http.Cookie{
-		Name:     "auth_token",
+		Name:     sessionCookieName,
 		Value:    "",
 		Path:     "/",
 		HttpOnly: true,
@@ -427,36 +777,15 @@ func (h *AuthHandler) GetUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get auth token from cookie or Authorization header
-	var tokenString string
-	cookie, err := r.Cookie("auth_token")
-	if err == nil {
-		tokenString = cookie.Value
-	} else {
-		// Try to get token from Authorization header
-		authHeader := r.Header.Get("Authorization")
-		if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-			tokenString = authHeader[7:]
-		}
-	}
-
-	if tokenString == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	// Verify token
-	userID, err := h.authService.VerifyToken(tokenString)
-	// backend/go/internal/api/handlers/auth.go (continued)
-	
+	// Resolve the caller: an oauth2provider app token or a session cookie
+	ctx := r.Context()
+	userID, _, err := h.resolveIdentity(r)
 	if err != nil {
-		logger.ErrorLogger.Printf("Token verification failed: %v", err)
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	// Get user profile
-	ctx := r.Context()
 	user, err := h.authService.GetUserByID(ctx, userID)
 	if err != nil {
 		logger.ErrorLogger.Printf("Error getting user profile: %v", err)
@@ -475,34 +804,66 @@ func (h *AuthHandler) GetUserHandler(w http.ResponseWriter, r *http.Request) {
 // AuthMiddleware checks if the user is authenticated
 func (h *AuthHandler) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Get auth token from cookie or Authorization header
-		var tokenString string
-		cookie, err := r.Cookie("auth_token")
-		if err == nil {
-			tokenString = cookie.Value
-		} else {
-			// Try to get token from Authorization header
-			authHeader := r.Header.Get("Authorization")
-			if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-				tokenString = authHeader[7:]
+		// Let CORS preflight through unauthenticated; the browser never
+		// attaches cookies or an Authorization header to it anyway.
+		if h.skipAuthPreflight && r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Paths matching a configured skip-auth regex (health checks,
+		// webhook receivers, ...) bypass auth entirely.
+		for _, re := range h.skipAuthRegexes {
+			if re.MatchString(r.URL.Path) {
+				ctx := context.WithValue(r.Context(), "userID", "anonymous")
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
 			}
 		}
 
-		if tokenString == "" {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		// Calls from inside the trusted network (e.g. other internal
+		// services) bypass auth too.
+		if ip := h.clientIP(r); h.isTrustedIP(ip) {
+			ctx := context.WithValue(r.Context(), "userID", "trusted:"+ip)
+			next.ServeHTTP(w, r.WithContext(ctx))
 			return
 		}
 
-		// Verify token
-		userID, err := h.authService.VerifyToken(tokenString)
+		// App tokens issued via the oauth2provider flow are presented as
+		// a bearer token and carry their own granted scopes; try that
+		// before falling back to a session cookie.
+		if authHeader := r.Header.Get("Authorization"); len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+			token := authHeader[7:]
+			if userID, _, scopes, err := h.authService.ValidateAppToken(token); err == nil {
+				h.attachForwardedIdentityForUser(r, userID, token)
+				ctx := context.WithValue(r.Context(), "userID", userID)
+				ctx = context.WithValue(ctx, "scopes", scopes)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+		}
+
+		// Resolve the session, refreshing the provider tokens if they're due
+		sess, err := h.resolveSession(r.Context(), r)
 		if err != nil {
-			logger.ErrorLogger.Printf("Token verification failed: %v", err)
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
+		h.attachForwardedIdentityForUser(r, sess.UserID, sess.AccessToken)
 
 		// Add user ID to request context
-		ctx := context.WithValue(r.Context(), "userID", userID)
+		ctx := context.WithValue(r.Context(), "userID", sess.UserID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
+}
+
+// attachForwardedIdentityForUser looks up userID's email and stamps the
+// forwarded identity headers (plus Sage-Signature) onto r before it's
+// passed along to the next handler/proxy in the chain.
+func (h *AuthHandler) attachForwardedIdentityForUser(r *http.Request, userID, accessToken string) {
+	var email string
+	if user, err := h.authService.GetUserByID(r.Context(), userID); err == nil && user != nil {
+		email = user.Email
+	}
+	attachForwardedIdentity(r, userID, email, accessToken)
 }
\ No newline at end of file
@@ -0,0 +1,405 @@
+// backend/go/internal/api/handlers/oauth_provider.go
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"sage-ai-v2/internal/services/oauth2provider"
+	"sage-ai-v2/pkg/logger"
+)
+
+// authorizationCodeTTL bounds how long a consent-granted code stays
+// redeemable before TokenHandler must exchange it.
+const authorizationCodeTTL = 2 * time.Minute
+
+// consentTokenTTL bounds how long a rendered consent page stays valid
+// before the user has to start the authorize flow over.
+const consentTokenTTL = 5 * time.Minute
+
+// OAuthProviderHandler implements sage-ai's own OAuth2 authorization
+// server, so third-party apps can call the sage-ai API on a user's
+// behalf instead of sharing that user's credentials. AuthorizeHandler
+// expects to run behind AuthHandler.AuthMiddleware so r.Context() already
+// carries "userID" for the signed-in user granting consent.
+type OAuthProviderHandler struct {
+	store       oauth2provider.Store
+	authService AuthServiceInterface
+}
+
+// NewOAuthProviderHandler builds an OAuthProviderHandler.
+func NewOAuthProviderHandler(store oauth2provider.Store, authService AuthServiceInterface) *OAuthProviderHandler {
+	return &OAuthProviderHandler{store: store, authService: authService}
+}
+
+type registerAppRequest struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+}
+
+// RegisterAppHandler handles POST /api/oauth/apps, registering a new
+// third-party client and returning its client_id/client_secret. The
+// secret is only ever returned here; the store only keeps its hash.
+func (h *OAuthProviderHandler) RegisterAppHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req registerAppRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.ErrorLogger.Printf("Error parsing app registration request: %v", err)
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" || len(req.RedirectURIs) == 0 {
+		http.Error(w, "name and redirect_uris are required", http.StatusBadRequest)
+		return
+	}
+
+	clientID, clientSecret, secretHash, err := oauth2provider.GenerateClientCredentials()
+	if err != nil {
+		logger.ErrorLogger.Printf("Error generating client credentials: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	client := &oauth2provider.Client{
+		ID:           clientID,
+		SecretHash:   secretHash,
+		Name:         req.Name,
+		RedirectURIs: req.RedirectURIs,
+		CreatedAt:    time.Now(),
+	}
+	if err := h.store.CreateClient(client); err != nil {
+		logger.ErrorLogger.Printf("Error creating oauth client: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"client_id":     clientID,
+		"client_secret": clientSecret,
+		"name":          client.Name,
+		"redirect_uris": client.RedirectURIs,
+	})
+}
+
+// AuthorizeHandler handles /api/oauth/authorize. GET renders a consent
+// page for the requested client/scope and mints a single-use consent
+// token bound to that exact request; POST carries the user's decision
+// back along with that token. Splitting the decision onto its own POST,
+// gated on a token the page itself handed out, is what stops a forged or
+// bookmarked GET from silently granting access (see decideAuthorize).
+func (h *OAuthProviderHandler) AuthorizeHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.renderAuthorize(w, r)
+	case http.MethodPost:
+		h.decideAuthorize(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *OAuthProviderHandler) renderAuthorize(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(string)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	if q.Get("response_type") != "code" {
+		http.Error(w, "Unsupported response_type", http.StatusBadRequest)
+		return
+	}
+
+	clientID := q.Get("client_id")
+	redirectURI := q.Get("redirect_uri")
+	scope := q.Get("scope")
+
+	client, err := h.store.GetClient(clientID)
+	if err != nil {
+		http.Error(w, "Unknown client", http.StatusBadRequest)
+		return
+	}
+	if !client.HasRedirectURI(redirectURI) {
+		http.Error(w, "redirect_uri is not registered for this client", http.StatusBadRequest)
+		return
+	}
+
+	token, err := oauth2provider.GenerateConsentToken()
+	if err != nil {
+		logger.ErrorLogger.Printf("Error generating consent token: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	consent := &oauth2provider.PendingConsent{
+		Token:               token,
+		ClientID:            clientID,
+		UserID:              userID,
+		RedirectURI:         redirectURI,
+		Scopes:              strings.Fields(scope),
+		CodeChallenge:       q.Get("code_challenge"),
+		CodeChallengeMethod: q.Get("code_challenge_method"),
+		State:               q.Get("state"),
+		ExpiresAt:           time.Now().Add(consentTokenTTL),
+	}
+	if err := h.store.SaveConsent(consent); err != nil {
+		logger.ErrorLogger.Printf("Error saving pending consent: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	renderConsentPage(w, client, scope, token)
+}
+
+// decideAuthorize handles the user's allow/deny decision, posted from the
+// form renderAuthorize rendered. The decision is trusted only because it
+// carries consent_token: that token was minted for one specific signed-in
+// user and authorization request, so a cross-site POST (which can't read
+// or supply that token) can't forge a decision on the victim's behalf.
+func (h *OAuthProviderHandler) decideAuthorize(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(string)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	consent, err := h.store.ConsumeConsent(r.FormValue("consent_token"))
+	if err != nil {
+		logger.ErrorLogger.Printf("Error consuming consent token: %v", err)
+		http.Error(w, "Invalid or expired consent request", http.StatusBadRequest)
+		return
+	}
+	// The token must be redeemed by the same user it was issued to, or a
+	// signed-in attacker could trick a victim into starting the flow and
+	// then submit the resulting consent_token themselves.
+	if consent.UserID != userID {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	client, err := h.store.GetClient(consent.ClientID)
+	if err != nil {
+		http.Error(w, "Unknown client", http.StatusBadRequest)
+		return
+	}
+	if !client.HasRedirectURI(consent.RedirectURI) {
+		http.Error(w, "redirect_uri is not registered for this client", http.StatusBadRequest)
+		return
+	}
+
+	if r.FormValue("consent") != "allow" {
+		if err := redirectWithParams(w, r, consent.RedirectURI, map[string]string{
+			"error": "access_denied",
+			"state": consent.State,
+		}); err != nil {
+			logger.ErrorLogger.Printf("Error building denial redirect: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	code, err := oauth2provider.GenerateAuthorizationCode()
+	if err != nil {
+		logger.ErrorLogger.Printf("Error generating authorization code: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	ac := &oauth2provider.AuthorizationCode{
+		Code:                code,
+		ClientID:            consent.ClientID,
+		UserID:              userID,
+		RedirectURI:         consent.RedirectURI,
+		Scopes:              consent.Scopes,
+		CodeChallenge:       consent.CodeChallenge,
+		CodeChallengeMethod: consent.CodeChallengeMethod,
+		ExpiresAt:           time.Now().Add(authorizationCodeTTL),
+	}
+	if err := h.store.SaveCode(ac); err != nil {
+		logger.ErrorLogger.Printf("Error saving authorization code: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := redirectWithParams(w, r, consent.RedirectURI, map[string]string{
+		"code":  code,
+		"state": consent.State,
+	}); err != nil {
+		logger.ErrorLogger.Printf("Error building authorization redirect: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// redirectWithParams 302s the browser to redirectURI with params merged
+// into its existing query string. redirectURI is a registered client
+// callback that may legitimately carry its own query string already
+// (e.g. "https://app.example.com/callback?tenant=acme"), so params must
+// be merged through net/url rather than appended with a bare "?"/"&",
+// which would either corrupt an existing query string or combine two "?"
+// into a malformed URL.
+func redirectWithParams(w http.ResponseWriter, r *http.Request, redirectURI string, params map[string]string) error {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return fmt.Errorf("invalid redirect_uri: %w", err)
+	}
+
+	q := u.Query()
+	for k, v := range params {
+		q.Set(k, v)
+	}
+	u.RawQuery = q.Encode()
+
+	http.Redirect(w, r, u.String(), http.StatusFound)
+	return nil
+}
+
+// renderConsentPage renders a minimal "allow this app" page that posts
+// the user's decision, plus the consent token that authorizes it, back
+// to the same /api/oauth/authorize URL.
+func renderConsentPage(w http.ResponseWriter, client *oauth2provider.Client, scope, consentToken string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<head><title>Authorize %s</title></head>
+<body>
+<h1>%s is requesting access</h1>
+<p>Requested scopes: %s</p>
+<form method="POST">
+<input type="hidden" name="consent_token" value="%s">
+<button type="submit" name="consent" value="allow">Allow</button>
+<button type="submit" name="consent" value="deny">Deny</button>
+</form>
+</body>
+</html>`,
+		html.EscapeString(client.Name), html.EscapeString(client.Name), html.EscapeString(scope),
+		html.EscapeString(consentToken))
+}
+
+// TokenHandler handles POST /api/oauth/token, supporting the
+// authorization_code and refresh_token grants.
+func (h *OAuthProviderHandler) TokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	client, err := h.store.GetClient(r.FormValue("client_id"))
+	if err != nil || !oauth2provider.VerifyClientSecret(client, r.FormValue("client_secret")) {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+
+	switch r.FormValue("grant_type") {
+	case "authorization_code":
+		h.issueTokenForCode(w, r, client)
+	case "refresh_token":
+		h.issueTokenForRefresh(w, r, client)
+	default:
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type")
+	}
+}
+
+func (h *OAuthProviderHandler) issueTokenForCode(w http.ResponseWriter, r *http.Request, client *oauth2provider.Client) {
+	ac, err := h.store.ConsumeCode(r.FormValue("code"))
+	if err != nil {
+		logger.ErrorLogger.Printf("Error consuming authorization code: %v", err)
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+	if ac.ClientID != client.ID || ac.RedirectURI != r.FormValue("redirect_uri") {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+	if !oauth2provider.VerifyPKCE(r.FormValue("code_verifier"), ac.CodeChallenge, ac.CodeChallengeMethod) {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	resp, err := h.authService.IssueAppToken(r.Context(), ac.UserID, client.ID, ac.Scopes)
+	if err != nil {
+		logger.ErrorLogger.Printf("Error issuing app token: %v", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *OAuthProviderHandler) issueTokenForRefresh(w http.ResponseWriter, r *http.Request, client *oauth2provider.Client) {
+	userID, issuedTo, scopes, err := h.authService.ValidateAppToken(r.FormValue("refresh_token"))
+	if err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+	// RFC 6749 6: a refresh token is only valid in the hands of the client
+	// it was issued to, so a token leaked to (or self-issued by) a
+	// different registered client can't be redeemed there.
+	if issuedTo != client.ID {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	resp, err := h.authService.IssueAppToken(r.Context(), userID, client.ID, scopes)
+	if err != nil {
+		logger.ErrorLogger.Printf("Error refreshing app token: %v", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// RevokeHandler handles POST /api/oauth/revoke. Per RFC 7009 it returns
+// 200 whether or not the token existed, so callers can't use it to probe
+// token validity.
+func (h *OAuthProviderHandler) RevokeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	token := r.FormValue("token")
+	if token == "" {
+		http.Error(w, "token is required", http.StatusBadRequest)
+		return
+	}
+	if err := h.authService.RevokeAppToken(token); err != nil {
+		logger.ErrorLogger.Printf("Error revoking app token: %v", err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
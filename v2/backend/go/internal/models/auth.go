@@ -0,0 +1,52 @@
+package models
+
+// SignInRequest is the payload for email/password sign-in.
+type SignInRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// SignUpRequest is the payload for email/password registration.
+type SignUpRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Name     string `json:"name,omitempty"`
+}
+
+// AuthResponse is returned by AuthService on a successful sign-in/sign-up.
+// For a user sign-in, RefreshToken and IDToken are persisted server-side
+// in a session.Session and never reach the browser directly. An app token
+// from IssueAppToken is encoded in full, refresh_token included, to the
+// third-party client by the /api/oauth/token endpoint.
+type AuthResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in"`
+	User         *User  `json:"user,omitempty"`
+}
+
+// User is a sage-ai account profile.
+type User struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+// PublicAuthResponse is what sign-in/sign-up/OAuth handlers actually
+// return to the browser: the session itself is server-side, so the
+// client only needs to know who it's signed in as and when that expires,
+// never the provider tokens in AuthResponse.
+type PublicAuthResponse struct {
+	User      *User `json:"user,omitempty"`
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+// NewPublicAuthResponse strips the provider tokens off resp, leaving only
+// what's safe to serialize back to the browser.
+func NewPublicAuthResponse(resp *AuthResponse) *PublicAuthResponse {
+	return &PublicAuthResponse{
+		User:      resp.User,
+		ExpiresIn: resp.ExpiresIn,
+	}
+}
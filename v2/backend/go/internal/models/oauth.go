@@ -0,0 +1,13 @@
+package models
+
+// OAuthRequest is the payload sent by the client to complete an OAuth
+// sign-in/sign-up flow after the provider redirects back with a code.
+type OAuthRequest struct {
+	Code        string `json:"code"`
+	RedirectURI string `json:"redirect_uri"`
+	// State must match the state value minted by OAuthURLHandler and
+	// stashed in the signed oauth_state cookie. It binds the callback to
+	// the browser session that started the flow and is rejected on
+	// mismatch to prevent CSRF/open-redirect on the provider callback.
+	State string `json:"state"`
+}
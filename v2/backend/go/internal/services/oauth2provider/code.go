@@ -0,0 +1,30 @@
+package oauth2provider
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+)
+
+// GenerateAuthorizationCode returns a random, URL-safe authorization code.
+func GenerateAuthorizationCode() (string, error) {
+	return generateOpaqueToken("authorization code")
+}
+
+// GenerateConsentToken returns a random, URL-safe token minted when the
+// consent page is rendered and consumed when the user's decision is
+// submitted, binding the two together so the decision can't be forged or
+// replayed against a different authorization request.
+func GenerateConsentToken() (string, error) {
+	return generateOpaqueToken("consent token")
+}
+
+// generateOpaqueToken returns a random, URL-safe token, using label only
+// to annotate the error if the system CSPRNG fails.
+func generateOpaqueToken(label string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate %s: %w", label, err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
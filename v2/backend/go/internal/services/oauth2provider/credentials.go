@@ -0,0 +1,57 @@
+package oauth2provider
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+)
+
+// GenerateClientCredentials mints a new client_id/client_secret pair and
+// the hash that should be persisted on the Client record. The plaintext
+// secret is only ever returned once, at registration time.
+func GenerateClientCredentials() (clientID, clientSecret, secretHash string, err error) {
+	idBuf := make([]byte, 16)
+	if _, err := rand.Read(idBuf); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate client id: %w", err)
+	}
+	clientID = "sage_" + base64.RawURLEncoding.EncodeToString(idBuf)
+
+	secretBuf := make([]byte, 32)
+	if _, err := rand.Read(secretBuf); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate client secret: %w", err)
+	}
+	clientSecret = base64.RawURLEncoding.EncodeToString(secretBuf)
+
+	secretHash = hashSecret(clientSecret)
+	return clientID, clientSecret, secretHash, nil
+}
+
+// VerifyClientSecret reports whether secret matches the client's stored
+// hash, in constant time.
+func VerifyClientSecret(client *Client, secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(client.SecretHash)) == 1
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// VerifyPKCE reports whether verifier matches challenge under method.
+// Only S256 is supported, per RFC 7636's recommendation; "plain" is
+// rejected rather than silently allowed.
+func VerifyPKCE(verifier, challenge, method string) bool {
+	if challenge == "" {
+		// Client didn't send a code_challenge at /authorize, so there's
+		// nothing to verify at /token.
+		return true
+	}
+	if method != "S256" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
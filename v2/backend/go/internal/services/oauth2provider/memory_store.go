@@ -0,0 +1,91 @@
+package oauth2provider
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-process Store implementation. It's enough for a
+// single-instance deployment or local development; a multi-instance
+// deployment should back this interface with a shared database instead.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	clients  map[string]*Client
+	codes    map[string]*AuthorizationCode
+	consents map[string]*PendingConsent
+}
+
+// NewMemoryStore builds an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		clients:  make(map[string]*Client),
+		codes:    make(map[string]*AuthorizationCode),
+		consents: make(map[string]*PendingConsent),
+	}
+}
+
+func (m *MemoryStore) CreateClient(client *Client) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clients[client.ID] = client
+	return nil
+}
+
+func (m *MemoryStore) GetClient(clientID string) (*Client, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	client, ok := m.clients[clientID]
+	if !ok {
+		return nil, ErrClientNotFound
+	}
+	return client, nil
+}
+
+func (m *MemoryStore) SaveCode(code *AuthorizationCode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.codes[code.Code] = code
+	return nil
+}
+
+func (m *MemoryStore) ConsumeCode(code string) (*AuthorizationCode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ac, ok := m.codes[code]
+	if !ok {
+		return nil, ErrCodeNotFound
+	}
+	if ac.Used {
+		return nil, ErrCodeUsed
+	}
+	if time.Now().After(ac.ExpiresAt) {
+		return nil, ErrCodeExpired
+	}
+
+	ac.Used = true
+	return ac, nil
+}
+
+func (m *MemoryStore) SaveConsent(consent *PendingConsent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.consents[consent.Token] = consent
+	return nil
+}
+
+func (m *MemoryStore) ConsumeConsent(token string) (*PendingConsent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pc, ok := m.consents[token]
+	if !ok {
+		return nil, ErrConsentNotFound
+	}
+	delete(m.consents, token)
+
+	if time.Now().After(pc.ExpiresAt) {
+		return nil, ErrConsentExpired
+	}
+	return pc, nil
+}
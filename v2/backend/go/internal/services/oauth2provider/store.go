@@ -0,0 +1,89 @@
+// Package oauth2provider turns sage-ai into an OAuth2 authorization
+// server in its own right, so third-party apps can call the sage-ai API
+// on behalf of a user instead of sharing that user's own credentials.
+package oauth2provider
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrClientNotFound  = errors.New("oauth client not found")
+	ErrCodeNotFound    = errors.New("authorization code not found")
+	ErrCodeExpired     = errors.New("authorization code expired")
+	ErrCodeUsed        = errors.New("authorization code already used")
+	ErrConsentNotFound = errors.New("consent request not found")
+	ErrConsentExpired  = errors.New("consent request expired")
+)
+
+// Client is a third-party application registered to call the sage-ai API.
+type Client struct {
+	ID           string    `json:"client_id"`
+	SecretHash   string    `json:"-"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// HasRedirectURI reports whether uri is one of the client's registered
+// redirect URIs. Authorize and token exchanges must only ever redirect to
+// a URI the client owner registered up front.
+func (c *Client) HasRedirectURI(uri string) bool {
+	for _, r := range c.RedirectURIs {
+		if r == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthorizationCode is the short-lived code minted by AuthorizeHandler
+// once the user approves consent, and redeemed once by TokenHandler.
+type AuthorizationCode struct {
+	Code                string
+	ClientID            string
+	UserID              string
+	RedirectURI         string
+	Scopes              []string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	ExpiresAt           time.Time
+	Used                bool
+}
+
+// PendingConsent is the authorization request AuthorizeHandler is showing
+// the user a consent page for. It's keyed by an opaque, single-use Token
+// handed back to the browser as a hidden form field, so the POST that
+// carries the user's decision can only proceed if it also proves it saw
+// that exact consent page - the CSRF protection on the decision step.
+type PendingConsent struct {
+	Token               string
+	ClientID            string
+	UserID              string
+	RedirectURI         string
+	Scopes              []string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	State               string
+	ExpiresAt           time.Time
+}
+
+// Store persists registered clients, in-flight authorization codes, and
+// pending consent requests.
+type Store interface {
+	CreateClient(client *Client) error
+	GetClient(clientID string) (*Client, error)
+
+	SaveCode(code *AuthorizationCode) error
+	// ConsumeCode looks up code, marking it Used so it can't be redeemed
+	// twice, and returns ErrCodeUsed/ErrCodeExpired/ErrCodeNotFound as
+	// appropriate instead of a reusable record.
+	ConsumeCode(code string) (*AuthorizationCode, error)
+
+	SaveConsent(consent *PendingConsent) error
+	// ConsumeConsent looks up a pending consent by its token and deletes
+	// it so it can't be replayed, returning ErrConsentExpired/
+	// ErrConsentNotFound as appropriate instead of a reusable record.
+	ConsumeConsent(token string) (*PendingConsent, error)
+}
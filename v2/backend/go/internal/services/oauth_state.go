@@ -0,0 +1,121 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrOAuthStateMismatch describes a state decoded from the oauth_state
+// cookie that does not match the state the client sent back. No function
+// in this file returns it; OAuthSignInHandler compares the two itself.
+var ErrOAuthStateMismatch = errors.New("oauth state mismatch")
+
+// oauthStateEncryptionKey loads the AES-256 key used to encrypt the
+// oauth_state cookie from the environment. It mirrors how the rest of
+// this package keeps secrets out of source and config structs for now.
+func oauthStateEncryptionKey() ([]byte, error) {
+	key := os.Getenv("OAUTH_STATE_ENCRYPTION_KEY")
+	if len(key) != 32 {
+		return nil, fmt.Errorf("OAUTH_STATE_ENCRYPTION_KEY must be 32 bytes, got %d", len(key))
+	}
+	return []byte(key), nil
+}
+
+// GenerateOAuthState returns a random, base64url-encoded 32-byte state
+// value used to bind an OAuth authorization request to the browser
+// session that started it.
+func GenerateOAuthState() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate oauth state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// GeneratePKCE returns a random code_verifier and its S256 code_challenge
+// as defined by RFC 7636.
+func GeneratePKCE() (verifier string, challenge string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate code verifier: %w", err)
+	}
+	verifier = base64.RawURLEncoding.EncodeToString(buf)
+
+	sum := sha256.Sum256([]byte(verifier))
+	challenge = base64.RawURLEncoding.EncodeToString(sum[:])
+	return verifier, challenge, nil
+}
+
+// EncryptOAuthState seals state and codeVerifier into a single opaque,
+// base64url-encoded value suitable for storing in the oauth_state cookie.
+func EncryptOAuthState(state, codeVerifier string) (string, error) {
+	key, err := oauthStateEncryptionKey()
+	if err != nil {
+		return "", err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", fmt.Errorf("failed to create gcm: %w", err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	plaintext := []byte(state + ":" + codeVerifier)
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptOAuthState reverses EncryptOAuthState, returning the original
+// state and codeVerifier.
+func DecryptOAuthState(value string) (state string, codeVerifier string, err error) {
+	key, err := oauthStateEncryptionKey()
+	if err != nil {
+		return "", "", err
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid oauth_state cookie: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create gcm: %w", err)
+	}
+
+	nonceSize := gcm.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", "", errors.New("oauth_state cookie too short")
+	}
+	nonce, ciphertext := sealed[:nonceSize], sealed[nonceSize:]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to decrypt oauth_state cookie: %w", err)
+	}
+
+	parts := strings.SplitN(string(plaintext), ":", 2)
+	if len(parts) != 2 {
+		return "", "", errors.New("malformed oauth_state payload")
+	}
+	return parts[0], parts[1], nil
+}
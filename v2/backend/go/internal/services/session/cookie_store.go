@@ -0,0 +1,91 @@
+package session
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"sync"
+)
+
+// CookieStore is the default Store implementation: sessions are encrypted
+// with AES-GCM and kept in process memory keyed by a random ID, which is
+// the only thing that ever goes into the session cookie. It's suitable
+// for a single-instance deployment; multi-instance deployments should use
+// a RedisStore instead so all instances see the same sessions.
+type CookieStore struct {
+	mu       sync.RWMutex
+	sessions map[string][]byte
+	gcm      cipher.AEAD
+}
+
+// NewCookieStore builds a CookieStore, reading its AES-256 encryption key
+// from the SESSION_ENCRYPTION_KEY environment variable.
+func NewCookieStore() (*CookieStore, error) {
+	gcm, err := loadSessionGCM()
+	if err != nil {
+		return nil, err
+	}
+
+	return &CookieStore{
+		sessions: make(map[string][]byte),
+		gcm:      gcm,
+	}, nil
+}
+
+func (c *CookieStore) Save(sess *Session) (string, error) {
+	id, err := generateSessionID()
+	if err != nil {
+		return "", err
+	}
+	if err := c.Update(id, sess); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (c *CookieStore) Get(id string) (*Session, error) {
+	c.mu.RLock()
+	ciphertext, ok := c.sessions[id]
+	c.mu.RUnlock()
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+
+	sess, err := decryptSession(c.gcm, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	if sess.Expired() {
+		c.Delete(id)
+		return nil, ErrSessionNotFound
+	}
+	return sess, nil
+}
+
+func (c *CookieStore) Update(id string, sess *Session) error {
+	ciphertext, err := encryptSession(c.gcm, sess)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.sessions[id] = ciphertext
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *CookieStore) Delete(id string) error {
+	c.mu.Lock()
+	delete(c.sessions, id)
+	c.mu.Unlock()
+	return nil
+}
+
+func generateSessionID() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate session id: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,61 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// loadSessionGCM builds the AES-GCM cipher used to encrypt session
+// records at rest, reading its key from SESSION_ENCRYPTION_KEY. Every
+// Store implementation shares this so a session is encrypted the same
+// way regardless of backend.
+func loadSessionGCM() (cipher.AEAD, error) {
+	key := os.Getenv("SESSION_ENCRYPTION_KEY")
+	if len(key) != 32 {
+		return nil, fmt.Errorf("SESSION_ENCRYPTION_KEY must be 32 bytes, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptSession seals sess into opaque ciphertext for storage.
+func encryptSession(gcm cipher.AEAD, sess *Session) ([]byte, error) {
+	plaintext, err := json.Marshal(sess)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// decryptSession reverses encryptSession.
+func decryptSession(gcm cipher.AEAD, ciphertext []byte) (*Session, error) {
+	nonceSize := gcm.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, fmt.Errorf("malformed session record")
+	}
+	nonce, encrypted := ciphertext[:nonceSize], ciphertext[nonceSize:]
+
+	plaintext, err := gcm.Open(nil, nonce, encrypted, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt session: %w", err)
+	}
+
+	var sess Session
+	if err := json.Unmarshal(plaintext, &sess); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session: %w", err)
+	}
+	return &sess, nil
+}
@@ -0,0 +1,99 @@
+package session
+
+import (
+	"crypto/cipher"
+	"fmt"
+	"time"
+)
+
+// RedisClient is the minimal surface RedisStore needs. It's defined here
+// rather than importing a concrete Redis library so this package stays
+// dependency-free; wire up github.com/redis/go-redis/v9 (or similar) at
+// the call site that constructs a RedisStore.
+type RedisClient interface {
+	Set(key string, value []byte, ttl time.Duration) error
+	Get(key string) ([]byte, error)
+	Del(key string) error
+}
+
+// RedisStore is the multi-instance-safe Store backend: every instance
+// behind a load balancer reads/writes the same Redis keyspace, so a
+// session survives which instance happens to serve a given request.
+// Records are AES-GCM encrypted before they ever reach Redis, the same
+// as CookieStore, so read access to the keyspace (ops tooling, a backup,
+// a misconfigured ACL) doesn't hand out live provider tokens in the clear.
+type RedisStore struct {
+	client    RedisClient
+	keyPrefix string
+	gcm       cipher.AEAD
+}
+
+// NewRedisStore builds a RedisStore on top of client, namespacing keys
+// under keyPrefix (e.g. "sage-ai:session:") and reading its AES-256
+// encryption key from the SESSION_ENCRYPTION_KEY environment variable.
+func NewRedisStore(client RedisClient, keyPrefix string) (*RedisStore, error) {
+	gcm, err := loadSessionGCM()
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisStore{client: client, keyPrefix: keyPrefix, gcm: gcm}, nil
+}
+
+func (r *RedisStore) key(id string) string {
+	return r.keyPrefix + id
+}
+
+func (r *RedisStore) Save(sess *Session) (string, error) {
+	id, err := generateSessionID()
+	if err != nil {
+		return "", err
+	}
+	if err := r.Update(id, sess); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (r *RedisStore) Get(id string) (*Session, error) {
+	ciphertext, err := r.client.Get(r.key(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session from redis: %w", err)
+	}
+	if ciphertext == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	sess, err := decryptSession(r.gcm, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	if sess.Expired() {
+		r.Delete(id)
+		return nil, ErrSessionNotFound
+	}
+	return sess, nil
+}
+
+func (r *RedisStore) Update(id string, sess *Session) error {
+	ciphertext, err := encryptSession(r.gcm, sess)
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Until(sess.ExpiresAt)
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+	if err := r.client.Set(r.key(id), ciphertext, ttl); err != nil {
+		return fmt.Errorf("failed to write session to redis: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisStore) Delete(id string) error {
+	if err := r.client.Del(r.key(id)); err != nil {
+		return fmt.Errorf("failed to delete session from redis: %w", err)
+	}
+	return nil
+}
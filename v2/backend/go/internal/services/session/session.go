@@ -0,0 +1,55 @@
+// Package session provides encrypted, server-side session storage for
+// sage-ai's auth flow. Cookies only ever carry an opaque session ID; the
+// provider tokens live in the Store, so sign-out can actually revoke them
+// and refresh tokens never touch the browser.
+package session
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrSessionNotFound is returned by Store.Get when the session ID does not
+// resolve to a live (unexpired) session record.
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session is the record kept server-side for a signed-in user. CreatedAt
+// and ExpiresAt bound the session's total lifetime; RefreshAfter is the
+// earlier point at which AuthMiddleware should proactively call
+// AuthService.Refresh to rotate the provider access token.
+type Session struct {
+	UserID       string    `json:"user_id"`
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	IDToken      string    `json:"id_token"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	RefreshAfter time.Time `json:"refresh_after"`
+}
+
+// Expired reports whether the session has outlived ExpiresAt.
+func (s *Session) Expired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
+
+// NeedsRefresh reports whether the session is past RefreshAfter and should
+// be rotated before it's used again.
+func (s *Session) NeedsRefresh() bool {
+	return time.Now().After(s.RefreshAfter)
+}
+
+// Store persists Sessions keyed by an opaque ID. Implementations encrypt
+// the record at rest; the cookie handed to the browser only ever contains
+// the ID returned by Save.
+type Store interface {
+	// Save persists sess and returns the opaque session ID to cookie.
+	Save(sess *Session) (id string, err error)
+	// Get looks up the session for id. Returns ErrSessionNotFound if it
+	// doesn't exist or has expired.
+	Get(id string) (*Session, error)
+	// Update overwrites the session stored at id, e.g. after a refresh.
+	Update(id string, sess *Session) error
+	// Delete removes the session for id, making sign-out actually revoke
+	// the session instead of just clearing a client-side cookie.
+	Delete(id string) error
+}
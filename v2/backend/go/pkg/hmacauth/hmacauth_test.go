@@ -0,0 +1,49 @@
+package hmacauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVerifySignature_ValidSignature(t *testing.T) {
+	key := []byte("test-shared-key")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/resource", nil)
+	req.Header.Set("X-Forwarded-User", "user-123")
+	req.Header.Set("X-Forwarded-Email", "user@example.com")
+
+	req.Header.Set(SignatureHeader, Sign(key, req))
+
+	if err := VerifySignature(req, key); err != nil {
+		t.Fatalf("expected valid signature to verify, got error: %v", err)
+	}
+}
+
+func TestVerifySignature_TamperedHeaderFailsVerification(t *testing.T) {
+	key := []byte("test-shared-key")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/resource", nil)
+	req.Header.Set("X-Forwarded-User", "user-123")
+	req.Header.Set("X-Forwarded-Email", "user@example.com")
+
+	req.Header.Set(SignatureHeader, Sign(key, req))
+
+	// Tamper with a signed header after the signature was computed, as a
+	// rogue caller inside the network might try to spoof the user.
+	req.Header.Set("X-Forwarded-User", "attacker")
+
+	if err := VerifySignature(req, key); err != ErrInvalidSignature {
+		t.Fatalf("expected ErrInvalidSignature for tampered request, got: %v", err)
+	}
+}
+
+func TestVerifySignature_MissingSignature(t *testing.T) {
+	key := []byte("test-shared-key")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/resource", nil)
+
+	if err := VerifySignature(req, key); err != ErrMissingSignature {
+		t.Fatalf("expected ErrMissingSignature, got: %v", err)
+	}
+}